@@ -0,0 +1,258 @@
+// Package copier copies dataset files across a worker pool.
+//
+// Each file is streamed through a buffered "read-ahead" reader, written to
+// a temporary sibling file and atomically renamed into place so a failure
+// partway through never leaves a truncated file at the destination. It
+// optionally hashes both sides of the copy so callers can record proof of
+// a verified transfer, and can skip files a previous, interrupted run
+// already finished.
+package copier
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"sync"
+)
+
+// DefaultReadAhead is used when Options.ReadAhead is zero.
+const DefaultReadAhead = 4 * 1024 * 1024
+
+// Job describes a single file to copy.
+type Job struct {
+	Idx   int
+	Label string
+	// Partition is an optional split name (e.g. "train") a caller can use
+	// to group Results; copier itself ignores it.
+	Partition string
+	SrcPath   string
+	DstPath   string
+}
+
+// Result is the outcome of a successfully processed Job.
+type Result struct {
+	Job
+	// SHA256 is the hex-encoded digest of the file, set when Options.Verify
+	// is true.
+	SHA256 string
+	// Bytes is the number of bytes copied, or the size of the file that was
+	// left in place when Skipped is true.
+	Bytes int64
+	// Skipped is true when Options.Resume found a matching file already in
+	// place and the copy was not repeated.
+	Skipped bool
+}
+
+// Options configures a copier Run.
+type Options struct {
+	// Workers is the number of files copied concurrently. Values below 1
+	// are treated as 1.
+	Workers int
+	// ReadAhead is the size of the buffered reader used for each copy.
+	// Zero selects DefaultReadAhead.
+	ReadAhead int
+	// Verify hashes the source and the written destination with SHA256 and
+	// fails the job if they don't match.
+	Verify bool
+	// Resume skips a job whose destination already exists with a matching
+	// size and SHA256 hash, regardless of Verify.
+	Resume bool
+}
+
+// ProgressFunc is called after each job completes, in no particular job
+// order, so callers can report files/sec and bytes/sec.
+type ProgressFunc func(done, total int, bytesDone int64)
+
+// Run copies every job across a pool of workers and returns one Result per
+// job. It stops dispatching new jobs and returns the first error once any
+// job fails, though jobs already in flight are allowed to finish.
+func Run(jobs []Job, opts Options, onProgress ProgressFunc) ([]Result, error) {
+	workers := opts.Workers
+	if workers < 1 {
+		workers = 1
+	}
+
+	jobCh := make(chan Job)
+	type outcome struct {
+		res Result
+		err error
+	}
+	outCh := make(chan outcome)
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobCh {
+				res, err := copyOne(job, opts)
+				outCh <- outcome{res: res, err: err}
+			}
+		}()
+	}
+	stop := make(chan struct{})
+	go func() {
+		defer close(jobCh)
+		for _, job := range jobs {
+			select {
+			case jobCh <- job:
+			case <-stop:
+				return
+			}
+		}
+	}()
+	go func() {
+		wg.Wait()
+		close(outCh)
+	}()
+
+	results := make([]Result, 0, len(jobs))
+	var done int
+	var bytesDone int64
+	var firstErr error
+	for o := range outCh {
+		if o.err != nil {
+			if firstErr == nil {
+				firstErr = o.err
+				close(stop)
+			}
+			continue
+		}
+		results = append(results, o.res)
+		done++
+		bytesDone += o.res.Bytes
+		if onProgress != nil {
+			onProgress(done, len(jobs), bytesDone)
+		}
+	}
+	if firstErr != nil {
+		return results, firstErr
+	}
+	return results, nil
+}
+
+// copyOne performs a single, atomic copy, honoring Options.Resume and
+// Options.Verify.
+func copyOne(job Job, opts Options) (Result, error) {
+	res := Result{Job: job}
+
+	srcInfo, err := os.Stat(job.SrcPath)
+	if err != nil {
+		return res, err
+	}
+	res.Bytes = srcInfo.Size()
+
+	if opts.Resume {
+		skipped, hash, err := resumeMatch(job, srcInfo.Size())
+		if err != nil {
+			return res, err
+		}
+		if skipped {
+			res.Skipped = true
+			res.SHA256 = hash
+			return res, nil
+		}
+	}
+
+	src, err := os.Open(job.SrcPath)
+	if err != nil {
+		return res, err
+	}
+	defer src.Close()
+
+	tmpPath := job.DstPath + ".tmp"
+	dst, err := os.Create(tmpPath)
+	if err != nil {
+		return res, err
+	}
+
+	readAhead := opts.ReadAhead
+	if readAhead <= 0 {
+		readAhead = DefaultReadAhead
+	}
+	r := bufio.NewReaderSize(src, readAhead)
+
+	var srcHash hash.Hash
+	var w io.Writer = dst
+	if opts.Verify {
+		srcHash = sha256.New()
+		w = io.MultiWriter(dst, srcHash)
+	}
+
+	if _, err := io.Copy(w, r); err != nil {
+		dst.Close()
+		os.Remove(tmpPath)
+		return res, err
+	}
+	if err := dst.Close(); err != nil {
+		os.Remove(tmpPath)
+		return res, err
+	}
+	if err := os.Rename(tmpPath, job.DstPath); err != nil {
+		os.Remove(tmpPath)
+		return res, err
+	}
+
+	if opts.Verify {
+		dstHash, err := hashFile(job.DstPath)
+		if err != nil {
+			return res, err
+		}
+		if dstHash != hex.EncodeToString(srcHash.Sum(nil)) {
+			return res, fmt.Errorf("checksum mismatch copying %s to %s", job.SrcPath, job.DstPath)
+		}
+		res.SHA256 = dstHash
+	}
+
+	return res, nil
+}
+
+// resumeMatch reports whether job.DstPath already holds the file described
+// by job, so the copy can be skipped. It always hashes both sides: a
+// destination that merely has the right size could still be a different
+// file that landed on the same shuffled index, and serving a size-only
+// match would silently mislabel it.
+func resumeMatch(job Job, srcSize int64) (bool, string, error) {
+	dstInfo, err := os.Stat(job.DstPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, "", nil
+		}
+		return false, "", err
+	}
+	if dstInfo.Size() != srcSize {
+		return false, "", nil
+	}
+
+	srcHash, err := hashFile(job.SrcPath)
+	if err != nil {
+		return false, "", err
+	}
+	dstHash, err := hashFile(job.DstPath)
+	if err != nil {
+		return false, "", err
+	}
+	if srcHash != dstHash {
+		return false, "", nil
+	}
+	return true, dstHash, nil
+}
+
+// hashFile returns the hex-encoded SHA256 digest of the file at path.
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
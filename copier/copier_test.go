@@ -0,0 +1,89 @@
+package copier
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("writeFile: %v", err)
+	}
+}
+
+func TestRunCopiesAndVerifies(t *testing.T) {
+	srcDir := t.TempDir()
+	dstDir := t.TempDir()
+
+	writeFile(t, filepath.Join(srcDir, "0"), "hello")
+	writeFile(t, filepath.Join(srcDir, "1"), "world")
+
+	jobs := []Job{
+		{Idx: 0, Label: "a", SrcPath: filepath.Join(srcDir, "0"), DstPath: filepath.Join(dstDir, "0")},
+		{Idx: 1, Label: "b", SrcPath: filepath.Join(srcDir, "1"), DstPath: filepath.Join(dstDir, "1")},
+	}
+
+	results, err := Run(jobs, Options{Workers: 2, Verify: true}, nil)
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if len(results) != len(jobs) {
+		t.Fatalf("expected %d results, got %d", len(jobs), len(results))
+	}
+	for _, res := range results {
+		if res.SHA256 == "" {
+			t.Errorf("job %d: expected a SHA256 digest", res.Idx)
+		}
+		if _, err := os.Stat(res.DstPath + ".tmp"); !os.IsNotExist(err) {
+			t.Errorf("job %d: temp file was not cleaned up", res.Idx)
+		}
+		if _, err := os.Stat(res.DstPath); err != nil {
+			t.Errorf("job %d: destination missing: %v", res.Idx, err)
+		}
+	}
+}
+
+func TestRunResumeSkipsCompletedFiles(t *testing.T) {
+	srcDir := t.TempDir()
+	dstDir := t.TempDir()
+
+	writeFile(t, filepath.Join(srcDir, "0"), "hello")
+	writeFile(t, filepath.Join(dstDir, "0"), "hello")
+
+	job := Job{Idx: 0, Label: "a", SrcPath: filepath.Join(srcDir, "0"), DstPath: filepath.Join(dstDir, "0")}
+
+	results, err := Run([]Job{job}, Options{Workers: 1, Resume: true}, nil)
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if !results[0].Skipped {
+		t.Errorf("expected the job to be skipped")
+	}
+}
+
+func TestRunResumeRecopiesMismatchedSize(t *testing.T) {
+	srcDir := t.TempDir()
+	dstDir := t.TempDir()
+
+	writeFile(t, filepath.Join(srcDir, "0"), "hello")
+	writeFile(t, filepath.Join(dstDir, "0"), "nope")
+
+	job := Job{Idx: 0, Label: "a", SrcPath: filepath.Join(srcDir, "0"), DstPath: filepath.Join(dstDir, "0")}
+
+	results, err := Run([]Job{job}, Options{Workers: 1, Resume: true}, nil)
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if results[0].Skipped {
+		t.Errorf("expected the mismatched file to be recopied")
+	}
+	got, err := os.ReadFile(filepath.Join(dstDir, "0"))
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if string(got) != "hello" {
+		t.Errorf("expected destination to be overwritten with source content, got %q", got)
+	}
+}
@@ -0,0 +1,25 @@
+// Package disk provides a portable way to query free space on a volume.
+//
+// The standard library only exposes raw filesystem stats through
+// syscall.Statfs, which is Unix-only; a caller that drops its error is left
+// with zeroed fields. This package wraps the platform-specific
+// calls (see disk_unix.go and disk_windows.go) behind a single Usage
+// function that always returns a non-nil error on failure.
+package disk
+
+// DiskStatus is the usage of a volume, in bytes, plus its inode usage
+// where the platform exposes one. InodesTotal and InodesFree are zero on
+// filesystems (such as Windows/NTFS) with no fixed inode count to report.
+type DiskStatus struct {
+	All  uint64
+	Used uint64
+	Free uint64
+
+	InodesTotal uint64
+	InodesFree  uint64
+}
+
+// Usage returns the disk usage of the volume containing path.
+func Usage(path string) (DiskStatus, error) {
+	return usage(path)
+}
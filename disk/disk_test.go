@@ -0,0 +1,30 @@
+package disk
+
+import "testing"
+
+func TestUsage(t *testing.T) {
+	dir := t.TempDir()
+
+	status, err := Usage(dir)
+	if err != nil {
+		t.Fatalf("Usage returned error: %v", err)
+	}
+	if status.All == 0 {
+		t.Errorf("expected All to be non-zero")
+	}
+	if status.Free > status.All {
+		t.Errorf("Free (%d) should not exceed All (%d)", status.Free, status.All)
+	}
+	if status.Used != status.All-status.Free {
+		t.Errorf("Used (%d) should equal All-Free (%d)", status.Used, status.All-status.Free)
+	}
+	if status.InodesFree > status.InodesTotal {
+		t.Errorf("InodesFree (%d) should not exceed InodesTotal (%d)", status.InodesFree, status.InodesTotal)
+	}
+}
+
+func TestUsageNonExistentPath(t *testing.T) {
+	if _, err := Usage("/path/that/does/not/exist/hopefully"); err == nil {
+		t.Errorf("expected an error for a non-existent path")
+	}
+}
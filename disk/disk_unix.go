@@ -0,0 +1,20 @@
+// +build !windows
+
+package disk
+
+import "syscall"
+
+// usage reads disk usage via Statfs on Unix-like systems.
+func usage(path string) (DiskStatus, error) {
+	var disk DiskStatus
+	fs := syscall.Statfs_t{}
+	if err := syscall.Statfs(path, &fs); err != nil {
+		return disk, err
+	}
+	disk.All = fs.Blocks * uint64(fs.Bsize)
+	disk.Free = fs.Bfree * uint64(fs.Bsize)
+	disk.Used = disk.All - disk.Free
+	disk.InodesTotal = uint64(fs.Files)
+	disk.InodesFree = uint64(fs.Ffree)
+	return disk, nil
+}
@@ -0,0 +1,41 @@
+// +build windows
+
+package disk
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+var (
+	kernel32             = syscall.NewLazyDLL("kernel32.dll")
+	procGetDiskFreeSpace = kernel32.NewProc("GetDiskFreeSpaceExW")
+)
+
+// usage reads disk usage via GetDiskFreeSpaceExW on Windows.
+func usage(path string) (DiskStatus, error) {
+	var disk DiskStatus
+	var freeBytes, totalBytes, totalFreeBytes uint64
+
+	pathPtr, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return disk, err
+	}
+
+	ret, _, err := procGetDiskFreeSpace.Call(
+		uintptr(unsafe.Pointer(pathPtr)),
+		uintptr(unsafe.Pointer(&freeBytes)),
+		uintptr(unsafe.Pointer(&totalBytes)),
+		uintptr(unsafe.Pointer(&totalFreeBytes)),
+	)
+	if ret == 0 {
+		return disk, err
+	}
+
+	disk.All = totalBytes
+	disk.Free = totalFreeBytes
+	disk.Used = disk.All - disk.Free
+	// NTFS has no fixed inode count to report; leave InodesTotal/InodesFree
+	// at zero so callers can tell inode accounting isn't available here.
+	return disk, nil
+}
@@ -3,12 +3,19 @@ package main
 import (
 	"bufio"
 	"fmt"
+	"github.com/Rompei/mkdataset/copier"
+	"github.com/Rompei/mkdataset/disk"
+	"github.com/Rompei/mkdataset/manifest"
+	"github.com/Rompei/mkdataset/split"
+	"github.com/Rompei/mkdataset/walker"
+	"github.com/dustin/go-humanize"
 	"github.com/jessevdk/go-flags"
-	"io"
 	"math/rand"
 	"os"
 	"path/filepath"
 	"regexp"
+	"runtime"
+	"sort"
 	"strconv"
 	"strings"
 	"syscall"
@@ -19,48 +26,25 @@ var comma = regexp.MustCompile(`^\.`)
 
 // Options is struct of Options.
 type Options struct {
-	Output       string `short:"o" long:"output" description:"output dir" default:"output"`
-	DataDir      string `short:"d" long:"datadir" description:"Directory of datasets." default:"./"`
-	IsMakeTxt    bool   `short:"t" long:"txt" description:"Making summary text file"`
-	Label        string `short:"l" long:"label" description:"Label of the dataset"`
-	TextFileName string `short:"f" long:"txtfname" description:"The name of text file"`
-	Prefix       string `short:"p" long:"prefix" description:"Prefix of the data."`
-	Suffix       string `short:"s" long:"suffix" description:"Suffix of the data."`
-}
-
-const (
-	// B is byte
-	B = 1
-	// KB is kilo byte
-	KB = 1024 * B
-	// MB is mega byte
-	MB = 1024 * KB
-	//GB is giga byte
-	GB = 1024 * MB
-)
-
-//DiskStatus is status of disk.
-type DiskStatus struct {
-	All  uint64 `json:"all"`
-	Used uint64 `json:"used"`
-	Free uint64 `json:"free"`
-}
-
-// diskUsage is disk usage of path/disk
-func diskUsage(path string) (disk DiskStatus) {
-	fs := syscall.Statfs_t{}
-	err := syscall.Statfs(path, &fs)
-	if err != nil {
-		return
-	}
-	disk.All = fs.Blocks * uint64(fs.Bsize)
-	disk.Free = fs.Bfree * uint64(fs.Bsize)
-	disk.Used = disk.All - disk.Free
-	return
+	Output        string `short:"o" long:"output" description:"output dir" default:"output"`
+	DataDir       string `short:"d" long:"datadir" description:"Directory of datasets." default:"./"`
+	IsMakeTxt     bool   `short:"t" long:"txt" description:"Making summary text file"`
+	Label         string `short:"l" long:"label" description:"Label of the dataset"`
+	TextFileName  string `short:"f" long:"txtfname" description:"The name of text file"`
+	Prefix        string `short:"p" long:"prefix" description:"Prefix of the data."`
+	Suffix        string `short:"s" long:"suffix" description:"Suffix of the data."`
+	MinFreeBytes  string `long:"min-free-bytes" description:"Minimum free space to keep on the target disk, e.g. 500MiB or 10GB" default:"30GB"`
+	MinFreeInodes uint64 `long:"min-free-inodes" description:"Minimum free inodes to keep on the target filesystem (ignored where the platform can't report inode usage)" default:"10000"`
+	Workers       int    `short:"w" long:"workers" description:"Number of files copied concurrently" default:"4"`
+	ReadAhead     string `long:"read-ahead" description:"Read-ahead buffer size per copy, e.g. 4MiB" default:"4MiB"`
+	Verify        bool   `long:"verify" description:"Verify each copy with a SHA256 checksum"`
+	Resume        bool   `long:"resume" description:"Skip files already copied by a previous, interrupted run"`
+	Split         string `long:"split" description:"Comma separated name=fraction partitions, e.g. train=0.8,val=0.1,test=0.1"`
+	Seed          int64  `long:"seed" description:"Seed for shuffling and splitting; 0 picks a random seed" default:"0"`
+	Manifest      string `long:"manifest" description:"YAML or JSON file mapping labels to source directories; enables multi-class ingestion in one pass"`
 }
 
 func main() {
-	rand.Seed(time.Now().UnixNano())
 	opts, err := parseFlags()
 	if err != nil {
 		panic(err)
@@ -80,11 +64,19 @@ func parseFlags() (*Options, error) {
 	if err != nil {
 		os.Exit(0)
 	}
-	if opts.IsMakeTxt {
+	if opts.IsMakeTxt && opts.Manifest == "" {
 		if opts.Label == "" || opts.TextFileName == "" {
 			return nil, fmt.Errorf("Label or text file name is not defined.")
 		}
 	}
+	if opts.Split != "" {
+		if _, err := split.ParseSpec(opts.Split); err != nil {
+			return nil, err
+		}
+	}
+	if opts.Resume && opts.Seed == 0 {
+		return nil, fmt.Errorf("--resume requires an explicit --seed; 0 picks a new random seed every run, so destination indices from an earlier run won't line up")
+	}
 	if !isFileExist(opts.Output) {
 		if err := os.MkdirAll(opts.Output, 0777); err != nil {
 			return nil, err
@@ -106,29 +98,127 @@ func isFileExist(fpath string) bool {
 	return true
 }
 
+// fileEntry is one file discovered by collectSingle or collectManifest,
+// already tagged with the label it should be copied under.
+type fileEntry struct {
+	path  string
+	label string
+	size  int64
+}
+
+// walkers fan out over subdirectories, bounded to one goroutine per CPU.
+var maxWalkers = runtime.NumCPU()
+
+// collectSingle concurrently walks root under the single --label
+// configured by opts.
+func collectSingle(root string, opts *Options) ([]fileEntry, error) {
+	found, err := walker.Walk(root, maxWalkers, func(info os.FileInfo, path string) (bool, error) {
+		return checkFilePath(info, path, opts.Prefix, opts.Suffix)
+	})
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]fileEntry, len(found))
+	for i, f := range found {
+		entries[i] = fileEntry{path: f.Path, label: opts.Label, size: f.Size}
+	}
+	sortEntries(entries)
+	return entries, nil
+}
+
+// collectManifest concurrently walks every source directory in m, tagging
+// each file with its manifest label.
+func collectManifest(m manifest.Manifest, opts *Options) ([]fileEntry, error) {
+	var entries []fileEntry
+	for _, label := range m.Labels() {
+		src := m[label]
+		found, err := walker.Walk(src.Dir, maxWalkers, func(info os.FileInfo, path string) (bool, error) {
+			return checkFilePath(info, path, src.Prefix, src.Suffix)
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range found {
+			entries = append(entries, fileEntry{path: f.Path, label: label, size: f.Size})
+		}
+	}
+	sortEntries(entries)
+	return entries, nil
+}
+
+// sortEntries puts entries in canonical (lexical path) order. walker.Walk
+// fans out across goroutines and returns files in scheduling order, which
+// varies run to run; process() shuffles and splits by position in entries,
+// so a stable order here is what makes --seed (and --resume, which relies
+// on the same index landing on the same file) actually reproducible.
+func sortEntries(entries []fileEntry) {
+	sort.Slice(entries, func(i, j int) bool { return entries[i].path < entries[j].path })
+}
+
 func walk(root string, opts *Options) error {
-	distStatus := diskUsage("/")
-	var amount int64
-	var paths []string
-	filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
-		ok, err := checkFilePath(info, path, opts)
+	var entries []fileEntry
+	var labelsIdx map[string]int
+	if opts.Manifest != "" {
+		m, err := manifest.Load(opts.Manifest)
 		if err != nil {
 			return err
-		} else if !ok {
-			return nil
 		}
-		// count a sum of file size.
-		amount += info.Size()
-		paths = append(paths, path)
-		return nil
-	})
+		entries, err = collectManifest(m, opts)
+		if err != nil {
+			return err
+		}
+		labelsIdx = m.Index()
+	} else {
+		var err error
+		entries, err = collectSingle(root, opts)
+		if err != nil {
+			return err
+		}
+	}
+	return process(entries, labelsIdx, opts)
+}
 
-	// Caluculate predicted capacity after saving data.
+func process(entries []fileEntry, labelsIdx map[string]int, opts *Options) error {
+	absOutput, err := filepath.Abs(opts.Output)
+	if err != nil {
+		return fmt.Errorf("failed to resolve output directory: %v", err)
+	}
+	distStatus, err := disk.Usage(absOutput)
+	if err != nil {
+		return fmt.Errorf("failed to read disk usage: %v", err)
+	}
+	minFreeBytes, err := humanize.ParseBytes(opts.MinFreeBytes)
+	if err != nil {
+		return fmt.Errorf("invalid --min-free-bytes %q: %v", opts.MinFreeBytes, err)
+	}
+
+	var amount int64
+	for _, e := range entries {
+		amount += e.size
+	}
+
+	// Caluculate predicted capacity after saving data. distStatus.Free and
+	// amount are compared before subtracting so an undersized disk can't
+	// underflow the unsigned predicted-capacity value.
+	if uint64(amount) > distStatus.Free || distStatus.Free-uint64(amount) < minFreeBytes {
+		return fmt.Errorf("disk capacity would drop below the %s minimum (dataset needs %s, only %s free)", humanize.IBytes(minFreeBytes), humanize.IBytes(uint64(amount)), humanize.IBytes(distStatus.Free))
+	}
 	capPredict := distStatus.Free - uint64(amount)
-	if float64(distStatus.Free-uint64(amount)) < 3e+10 {
-		return fmt.Errorf("Shortage of disk capacity.")
+
+	// Inode accounting is zero on platforms (e.g. Windows/NTFS) that don't
+	// report a fixed inode count; skip the check there rather than abort
+	// on a threshold the filesystem can't actually enforce.
+	if distStatus.InodesTotal > 0 {
+		var inodesPredict uint64
+		if distStatus.InodesFree > uint64(len(entries)) {
+			inodesPredict = distStatus.InodesFree - uint64(len(entries))
+		}
+		if inodesPredict < opts.MinFreeInodes {
+			return fmt.Errorf("disk capacity would drop below the %d free-inode minimum (predicted free inodes: %d)", opts.MinFreeInodes, inodesPredict)
+		}
 	}
-	res, err := waitUserAction(fmt.Sprintf("Disk capacity will be %.2f/%.2f(GB)(file num: %d). Do you continue? [Y/N]", float64(capPredict)/float64(GB), float64(distStatus.All)/float64(GB), len(paths)))
+
+	res, err := waitUserAction(fmt.Sprintf("Disk capacity will be %s / %s free (%d files, dataset %s). Do you continue? [Y/N]", humanize.IBytes(capPredict), humanize.IBytes(distStatus.Free), len(entries), humanize.IBytes(uint64(amount))))
 	if err != nil {
 		return err
 	}
@@ -137,43 +227,156 @@ func walk(root string, opts *Options) error {
 		return nil
 	}
 
+	seed := opts.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	rnd := rand.New(rand.NewSource(seed))
+
 	// Create indices.
-	indices := make([]int, len(paths))
-	for i := 0; i < len(paths); i++ {
+	indices := make([]int, len(entries))
+	for i := 0; i < len(entries); i++ {
 		indices[i] = i
 	}
 	// Shuffle indices.
-	shuffle(indices)
+	shuffle(indices, rnd)
 
-	// Walking dirs.
-	var out *os.File
-	if opts.IsMakeTxt {
-		var err error
-		out, err = os.Create(opts.TextFileName)
+	var partitionOf []string
+	if opts.Split != "" {
+		partitions, err := split.ParseSpec(opts.Split)
 		if err != nil {
 			return err
 		}
-		defer out.Close()
+		labels := make([]string, len(entries))
+		for i, e := range entries {
+			labels[i] = e.label
+		}
+		partitionOf = split.Assign(labels, partitions, rnd)
+	}
+
+	readAhead, err := humanize.ParseBytes(opts.ReadAhead)
+	if err != nil {
+		return fmt.Errorf("invalid --read-ahead %q: %v", opts.ReadAhead, err)
 	}
 
-	for i, sPath := range paths {
-		dPath, err := buildPath(indices[i], opts.Output, sPath)
+	jobs := make([]copier.Job, len(entries))
+	for i, e := range entries {
+		outDir := opts.Output
+		var partition string
+		if partitionOf != nil {
+			partition = partitionOf[i]
+			outDir = filepath.Join(opts.Output, partition)
+			if !isFileExist(outDir) {
+				if err := os.MkdirAll(outDir, 0777); err != nil {
+					return err
+				}
+			}
+		}
+		dPath, err := buildPath(indices[i], outDir, e.path)
 		if err != nil {
 			return err
 		}
-		if opts.IsMakeTxt {
-			_, err := out.WriteString(fmt.Sprintf("%s %s\n", dPath, opts.Label))
-			if err != nil {
+		jobs[i] = copier.Job{Idx: indices[i], Label: e.label, Partition: partition, SrcPath: e.path, DstPath: dPath}
+	}
+
+	start := time.Now()
+	results, err := copier.Run(jobs, copier.Options{
+		Workers:   opts.Workers,
+		ReadAhead: int(readAhead),
+		Verify:    opts.Verify,
+		Resume:    opts.Resume,
+	}, func(done, total int, bytesDone int64) {
+		elapsed := time.Since(start).Seconds()
+		var filesPerSec, mibPerSec float64
+		if elapsed > 0 {
+			filesPerSec = float64(done) / elapsed
+			mibPerSec = float64(bytesDone) / elapsed / float64(humanize.MiByte)
+		}
+		fmt.Fprintf(os.Stderr, "\r%.1f%%... (%d/%d files, %.1f files/s, %.1f MiB/s)", float64(done)/float64(total)*100, done, total, filesPerSec, mibPerSec)
+	})
+	fmt.Println()
+	if err != nil {
+		return err
+	}
+
+	sort.Slice(results, func(i, j int) bool { return results[i].Idx < results[j].Idx })
+
+	if labelsIdx != nil {
+		if err := writeLabelsFile(opts.Output, labelsIdx); err != nil {
+			return err
+		}
+	}
+
+	if partitionOf != nil {
+		writers := make(map[string]*os.File)
+		defer func() {
+			for _, w := range writers {
+				w.Close()
+			}
+		}()
+		for _, res := range results {
+			out, ok := writers[res.Partition]
+			if !ok {
+				out, err = os.Create(filepath.Join(opts.Output, res.Partition+".txt"))
+				if err != nil {
+					return err
+				}
+				writers[res.Partition] = out
+			}
+			if err := writeSummaryLine(out, res, opts.Verify, labelsIdx); err != nil {
 				return err
 			}
 		}
-		err = copyFile(sPath, dPath)
+	} else if opts.IsMakeTxt || labelsIdx != nil {
+		txtName := opts.TextFileName
+		if txtName == "" {
+			txtName = filepath.Join(opts.Output, "summary.txt")
+		}
+		out, err := os.Create(txtName)
 		if err != nil {
 			return err
 		}
-		fmt.Fprintf(os.Stderr, "\r%.1f%%...", float64(i)/float64(len(paths)-1)*100)
+		defer out.Close()
+		for _, res := range results {
+			if err := writeSummaryLine(out, res, opts.Verify, labelsIdx); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func writeSummaryLine(out *os.File, res copier.Result, verify bool, labelsIdx map[string]int) error {
+	label := res.Label
+	if labelsIdx != nil {
+		label = strconv.Itoa(labelsIdx[res.Label])
+	}
+	line := fmt.Sprintf("%s %s", res.DstPath, label)
+	if verify {
+		line += " " + res.SHA256
+	}
+	_, err := out.WriteString(line + "\n")
+	return err
+}
+
+// writeLabelsFile records the int -> name mapping used in Caffe-style
+// manifest summaries, one name per line at its integer label's position.
+func writeLabelsFile(outputDir string, labelsIdx map[string]int) error {
+	names := make([]string, len(labelsIdx))
+	for name, idx := range labelsIdx {
+		names[idx] = name
+	}
+	out, err := os.Create(filepath.Join(outputDir, "labels.txt"))
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	for _, name := range names {
+		if _, err := out.WriteString(name + "\n"); err != nil {
+			return err
+		}
 	}
-	fmt.Println()
 	return nil
 }
 
@@ -188,12 +391,12 @@ func buildPath(idx int, dir string, sPath string) (string, error) {
 	return dir + "/" + strconv.Itoa(idx) + filepath.Ext(sPath), nil
 }
 
-func checkFilePath(info os.FileInfo, path string, opts *Options) (bool, error) {
+func checkFilePath(info os.FileInfo, path, prefix, suffix string) (bool, error) {
 	var pre *regexp.Regexp
 	var suf *regexp.Regexp
 	var err error
-	if opts.Prefix != "" {
-		pre, err = regexp.Compile("^" + opts.Prefix)
+	if prefix != "" {
+		pre, err = regexp.Compile("^" + prefix)
 		if err != nil {
 			return false, err
 		}
@@ -201,8 +404,8 @@ func checkFilePath(info os.FileInfo, path string, opts *Options) (bool, error) {
 			return false, nil
 		}
 	}
-	if opts.Suffix != "" {
-		suf, err = regexp.Compile(opts.Suffix + "$")
+	if suffix != "" {
+		suf, err = regexp.Compile(suffix + "$")
 		if err != nil {
 			return false, err
 		}
@@ -220,28 +423,9 @@ func checkFilePath(info os.FileInfo, path string, opts *Options) (bool, error) {
 	return !info.IsDir(), nil
 }
 
-func copyFile(srcPath, dstPath string) error {
-	src, err := os.Open(srcPath)
-	if err != nil {
-		return err
-	}
-	defer src.Close()
-	dst, err := os.Create(dstPath)
-	if err != nil {
-		return err
-	}
-	defer dst.Close()
-
-	_, err = io.Copy(dst, src)
-	if err != nil {
-		return err
-	}
-	return nil
-}
-
-func shuffle(indices []int) {
+func shuffle(indices []int, rnd *rand.Rand) {
 	for i := range indices {
-		j := rand.Intn(i + 1)
+		j := rnd.Intn(i + 1)
 		indices[i], indices[j] = indices[j], indices[i]
 	}
 }
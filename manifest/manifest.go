@@ -0,0 +1,68 @@
+// Package manifest loads a file mapping dataset labels to their source
+// directories, so a single run can ingest every class in one pass instead
+// of one label per invocation.
+package manifest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Source is one label's data: a directory plus optional filename filters,
+// applied the same way as the top-level --prefix/--suffix flags.
+type Source struct {
+	Dir    string `yaml:"dir" json:"dir"`
+	Prefix string `yaml:"prefix" json:"prefix"`
+	Suffix string `yaml:"suffix" json:"suffix"`
+}
+
+// Manifest maps a label name to the Source that holds its files.
+type Manifest map[string]Source
+
+// Load reads a Manifest from path. Files named "*.json" are parsed as
+// JSON; anything else is parsed as YAML.
+func Load(path string) (Manifest, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	m := make(Manifest)
+	if strings.ToLower(filepath.Ext(path)) == ".json" {
+		if err := json.Unmarshal(data, &m); err != nil {
+			return nil, fmt.Errorf("failed to parse manifest %s as JSON: %v", path, err)
+		}
+		return m, nil
+	}
+	if err := yaml.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest %s as YAML: %v", path, err)
+	}
+	return m, nil
+}
+
+// Labels returns the manifest's label names in sorted order, so the
+// integer label IDs handed out by Index are stable across runs.
+func (m Manifest) Labels() []string {
+	labels := make([]string, 0, len(m))
+	for label := range m {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+	return labels
+}
+
+// Index assigns every label a stable integer ID in sorted order, for a
+// Caffe-style "path label_int" summary.
+func (m Manifest) Index() map[string]int {
+	idx := make(map[string]int, len(m))
+	for i, label := range m.Labels() {
+		idx[label] = i
+	}
+	return idx
+}
@@ -0,0 +1,55 @@
+package manifest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadYAML(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "manifest.yaml")
+	content := "cats:\n  dir: ./raw/cats\n  suffix: .jpg\ndogs:\n  dir: ./raw/dogs\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	m, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+	if m["cats"].Dir != "./raw/cats" || m["cats"].Suffix != ".jpg" {
+		t.Errorf("unexpected cats source: %+v", m["cats"])
+	}
+	if m["dogs"].Dir != "./raw/dogs" {
+		t.Errorf("unexpected dogs source: %+v", m["dogs"])
+	}
+}
+
+func TestLoadJSON(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "manifest.json")
+	content := `{"cats": {"dir": "./raw/cats", "suffix": ".jpg"}, "dogs": {"dir": "./raw/dogs"}}`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	m, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+	if m["cats"].Dir != "./raw/cats" || m["cats"].Suffix != ".jpg" {
+		t.Errorf("unexpected cats source: %+v", m["cats"])
+	}
+}
+
+func TestIndexIsStableAndSorted(t *testing.T) {
+	m := Manifest{
+		"dogs": Source{Dir: "./raw/dogs"},
+		"cats": Source{Dir: "./raw/cats"},
+	}
+	idx := m.Index()
+	if idx["cats"] != 0 || idx["dogs"] != 1 {
+		t.Errorf("expected sorted label IDs, got %+v", idx)
+	}
+}
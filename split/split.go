@@ -0,0 +1,108 @@
+// Package split assigns dataset items to named partitions (train, val,
+// test, ...) in fixed proportions, stratified by label so that every
+// label's items are split in the same ratio within each partition.
+package split
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Partition is one named slice of a split, e.g. "train" with a target
+// fraction of 0.8.
+type Partition struct {
+	Name string
+	Frac float64
+}
+
+// ParseSpec parses a spec such as "train=0.8,val=0.1,test=0.1" into
+// Partitions. Fractions must be positive and sum to (approximately) 1.
+func ParseSpec(spec string) ([]Partition, error) {
+	fields := strings.Split(spec, ",")
+	partitions := make([]Partition, 0, len(fields))
+	var sum float64
+	for _, field := range fields {
+		kv := strings.SplitN(strings.TrimSpace(field), "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid split entry %q, want name=fraction", field)
+		}
+		frac, err := strconv.ParseFloat(strings.TrimSpace(kv[1]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid fraction in %q: %v", field, err)
+		}
+		if frac <= 0 {
+			return nil, fmt.Errorf("fraction in %q must be positive", field)
+		}
+		partitions = append(partitions, Partition{Name: strings.TrimSpace(kv[0]), Frac: frac})
+		sum += frac
+	}
+	if len(partitions) == 0 {
+		return nil, fmt.Errorf("split spec is empty")
+	}
+	if sum < 0.999 || sum > 1.001 {
+		return nil, fmt.Errorf("split fractions must sum to 1, got %.4f", sum)
+	}
+	return partitions, nil
+}
+
+// Assign returns the partition name for every entry in labels, so that
+// len(result) == len(labels). Each distinct label is shuffled and divided
+// among partitions independently, preserving class ratios in every
+// partition. Assign is deterministic for a given rnd.
+func Assign(labels []string, partitions []Partition, rnd *rand.Rand) []string {
+	result := make([]string, len(labels))
+
+	byLabel := make(map[string][]int)
+	var order []string
+	for i, label := range labels {
+		if _, ok := byLabel[label]; !ok {
+			order = append(order, label)
+		}
+		byLabel[label] = append(byLabel[label], i)
+	}
+	sort.Strings(order)
+
+	for _, label := range order {
+		idxs := byLabel[label]
+		rnd.Shuffle(len(idxs), func(i, j int) { idxs[i], idxs[j] = idxs[j], idxs[i] })
+		counts := allocate(len(idxs), partitions)
+		pos := 0
+		for pi, partition := range partitions {
+			for c := 0; c < counts[pi]; c++ {
+				result[idxs[pos]] = partition.Name
+				pos++
+			}
+		}
+	}
+	return result
+}
+
+// allocate turns fractional partitions into integer counts that sum to
+// exactly n, using the largest-remainder method so rounding error doesn't
+// accumulate across labels.
+func allocate(n int, partitions []Partition) []int {
+	counts := make([]int, len(partitions))
+	remainders := make([]float64, len(partitions))
+	var assigned int
+	for i, partition := range partitions {
+		exact := partition.Frac * float64(n)
+		counts[i] = int(exact)
+		remainders[i] = exact - float64(counts[i])
+		assigned += counts[i]
+	}
+	for assigned < n {
+		best := 0
+		for i := 1; i < len(remainders); i++ {
+			if remainders[i] > remainders[best] {
+				best = i
+			}
+		}
+		counts[best]++
+		remainders[best] = -1
+		assigned++
+	}
+	return counts
+}
@@ -0,0 +1,64 @@
+package split
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestParseSpec(t *testing.T) {
+	partitions, err := ParseSpec("train=0.8,val=0.1,test=0.1")
+	if err != nil {
+		t.Fatalf("ParseSpec returned error: %v", err)
+	}
+	if len(partitions) != 3 {
+		t.Fatalf("expected 3 partitions, got %d", len(partitions))
+	}
+	if partitions[0].Name != "train" || partitions[0].Frac != 0.8 {
+		t.Errorf("unexpected first partition: %+v", partitions[0])
+	}
+}
+
+func TestParseSpecRejectsBadFractions(t *testing.T) {
+	if _, err := ParseSpec("train=0.8,val=0.5"); err == nil {
+		t.Errorf("expected an error when fractions don't sum to 1")
+	}
+	if _, err := ParseSpec("train"); err == nil {
+		t.Errorf("expected an error for a malformed entry")
+	}
+}
+
+func TestAssignIsStratifiedAndDeterministic(t *testing.T) {
+	labels := make([]string, 1000)
+	for i := range labels {
+		if i%2 == 0 {
+			labels[i] = "cat"
+		} else {
+			labels[i] = "dog"
+		}
+	}
+	partitions, err := ParseSpec("train=0.8,val=0.2")
+	if err != nil {
+		t.Fatalf("ParseSpec returned error: %v", err)
+	}
+
+	a := Assign(labels, partitions, rand.New(rand.NewSource(42)))
+	b := Assign(labels, partitions, rand.New(rand.NewSource(42)))
+	for i := range a {
+		if a[i] != b[i] {
+			t.Fatalf("Assign is not deterministic for a fixed seed at index %d: %s != %s", i, a[i], b[i])
+		}
+	}
+
+	counts := map[string]map[string]int{"cat": {}, "dog": {}}
+	for i, partition := range a {
+		counts[labels[i]][partition]++
+	}
+	for label, byPartition := range counts {
+		if got, want := byPartition["train"], 400; got != want {
+			t.Errorf("%s: expected %d train items, got %d", label, want, got)
+		}
+		if got, want := byPartition["val"], 100; got != want {
+			t.Errorf("%s: expected %d val items, got %d", label, want, got)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package walker
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+func TestWalkFindsFilesAcrossSubdirectories(t *testing.T) {
+	root := t.TempDir()
+	for _, rel := range []string{"a/1.txt", "a/b/2.txt", "c/3.txt", "4.txt"} {
+		path := filepath.Join(root, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+			t.Fatalf("MkdirAll: %v", err)
+		}
+		if err := os.WriteFile(path, []byte("data"), 0644); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+	}
+
+	entries, err := Walk(root, 4, func(info os.FileInfo, path string) (bool, error) {
+		return true, nil
+	})
+	if err != nil {
+		t.Fatalf("Walk returned error: %v", err)
+	}
+
+	var got []string
+	for _, e := range entries {
+		rel, err := filepath.Rel(root, e.Path)
+		if err != nil {
+			t.Fatalf("Rel: %v", err)
+		}
+		got = append(got, rel)
+		if e.Size != 4 {
+			t.Errorf("%s: expected size 4, got %d", rel, e.Size)
+		}
+	}
+	sort.Strings(got)
+
+	want := []string{"4.txt", "a/1.txt", "a/b/2.txt", "c/3.txt"}
+	if len(got) != len(want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("expected %v, got %v", want, got)
+			break
+		}
+	}
+}
+
+func TestWalkAppliesFilter(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "keep.txt"), []byte("x"), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "skip.txt"), []byte("x"), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	entries, err := Walk(root, 2, func(info os.FileInfo, path string) (bool, error) {
+		return info.Name() == "keep.txt", nil
+	})
+	if err != nil {
+		t.Fatalf("Walk returned error: %v", err)
+	}
+	if len(entries) != 1 || filepath.Base(entries[0].Path) != "keep.txt" {
+		t.Fatalf("expected only keep.txt, got %+v", entries)
+	}
+}